@@ -0,0 +1,11 @@
+package entities
+
+// RelationTuple -
+type RelationTuple struct {
+	Namespace       string
+	ObjectID        string
+	Relation        string
+	UsersetEntity   string
+	UsersetObjectID string
+	UsersetRelation string
+}
@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	mongorepo "github.com/Permify/permify/internal/repositories/mongo"
+)
+
+// ErrUnknownDriver -
+var ErrUnknownDriver = errors.New("unknown repository driver")
+
+// NewRelationTupleRepository builds the IRelationTupleRepository selected by
+// the repository.driver config knob. postgres is the existing SQL-backed
+// repository to use when driver is "postgres" (the default); mongoDatabase is
+// only consulted when driver is "mongo", in which case its indexes are
+// created (if missing) before the repository is handed back.
+func NewRelationTupleRepository(ctx context.Context, driver string, postgres IRelationTupleRepository, mongoDatabase *mongo.Database) (IRelationTupleRepository, error) {
+	switch driver {
+	case "", "postgres":
+		return postgres, nil
+	case "mongo":
+		repo := mongorepo.NewRelationTupleRepository(mongoDatabase)
+		if err := repo.EnsureIndexes(ctx); err != nil {
+			return nil, err
+		}
+		return repo, nil
+	default:
+		return nil, ErrUnknownDriver
+	}
+}
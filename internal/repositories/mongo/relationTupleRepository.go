@@ -0,0 +1,192 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/Permify/permify/internal/entities"
+	"github.com/Permify/permify/pkg/consistency"
+)
+
+// ErrPointInTimeReadsNotSupported is returned by QueryTuplesAt when asked to
+// pin a read to a revision: this adapter doesn't yet implement the
+// causally-consistent session + afterClusterTime plumbing point-in-time
+// reads need, so it refuses the request rather than silently returning a
+// possibly-stale snapshot under a consistency token's name.
+var ErrPointInTimeReadsNotSupported = errors.New("mongo: point-in-time reads pinned to a revision are not supported yet")
+
+// CollectionName is the collection relation tuples are stored in.
+const CollectionName = "relation_tuples"
+
+// DriverName identifies this backend inside a consistency.Token.
+const DriverName = "mongo"
+
+// clusterTimeRevision is the consistency.Revision this adapter puts in its
+// tokens. Its value is the local wall-clock time (UTC, RFC 3339) taken after
+// the write; the server's cluster time is not read.
+type clusterTimeRevision struct {
+	value string
+}
+
+func (r clusterTimeRevision) String() string {
+	return r.value
+}
+
+// relationTupleDocument is the on-disk shape of a single tuple, e.g.
+// `document:1#viewer@user:1` or `document:1#viewer@group:eng#member`.
+type relationTupleDocument struct {
+	Namespace       string `bson:"namespace"`
+	ObjectID        string `bson:"object_id"`
+	Relation        string `bson:"relation"`
+	UsersetEntity   string `bson:"userset_namespace"`
+	UsersetObjectID string `bson:"userset_object_id"`
+	UsersetRelation string `bson:"userset_relation"`
+}
+
+// RelationTupleRepository is a MongoDB-backed repositories.IRelationTupleRepository.
+type RelationTupleRepository struct {
+	collection *mongo.Collection
+}
+
+// NewRelationTupleRepository -
+func NewRelationTupleRepository(database *mongo.Database) *RelationTupleRepository {
+	return &RelationTupleRepository{
+		collection: database.Collection(CollectionName),
+	}
+}
+
+// EnsureIndexes creates the compound index backing the hot QueryTuples path
+// and the secondary index backing the reverse QueryTuplesBySubject path. It
+// should be called once, e.g. during service startup.
+func (r *RelationTupleRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "namespace", Value: 1},
+				{Key: "object_id", Value: 1},
+				{Key: "relation", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "userset_namespace", Value: 1},
+				{Key: "userset_object_id", Value: 1},
+				{Key: "userset_relation", Value: 1},
+			},
+		},
+	})
+
+	return err
+}
+
+// QueryTuples returns every tuple stored for (namespace, objectID, relation).
+func (r *RelationTupleRepository) QueryTuples(ctx context.Context, namespace string, objectID string, relation string) ([]entities.RelationTuple, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"namespace": namespace,
+		"object_id": objectID,
+		"relation":  relation,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	return decodeRelationTuples(ctx, cursor)
+}
+
+// QueryTuplesBySubject returns every tuple whose userset side matches the
+// given subject, i.e. the reverse of QueryTuples.
+func (r *RelationTupleRepository) QueryTuplesBySubject(ctx context.Context, subjectNamespace string, subjectID string, subjectRelation string) ([]entities.RelationTuple, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"userset_namespace": subjectNamespace,
+		"userset_object_id": subjectID,
+		"userset_relation":  subjectRelation,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	return decodeRelationTuples(ctx, cursor)
+}
+
+// QueryTuplesAt behaves like QueryTuples but pinned to revision.
+//
+// Point-in-time reads need a causally-consistent session pinned to revision
+// via Mongo's afterClusterTime read concern; that session plumbing isn't
+// wired up in this adapter yet, so a non-empty revision is rejected rather
+// than silently served from the latest snapshot.
+func (r *RelationTupleRepository) QueryTuplesAt(ctx context.Context, namespace string, objectID string, relation string, revision string) ([]entities.RelationTuple, error) {
+	if revision != "" {
+		return nil, ErrPointInTimeReadsNotSupported
+	}
+
+	return r.QueryTuples(ctx, namespace, objectID, relation)
+}
+
+// InsertTuple stores a single relation tuple and returns a consistency token
+// stamped with the local time after the write (see token).
+func (r *RelationTupleRepository) InsertTuple(ctx context.Context, tuple entities.RelationTuple) (consistency.Token, error) {
+	_, err := r.collection.InsertOne(ctx, toDocument(tuple))
+	if err != nil {
+		return "", err
+	}
+
+	return r.token()
+}
+
+// DeleteTuple removes a single relation tuple and returns a consistency token
+// stamped with the local time after the write (see token).
+func (r *RelationTupleRepository) DeleteTuple(ctx context.Context, tuple entities.RelationTuple) (consistency.Token, error) {
+	_, err := r.collection.DeleteOne(ctx, toDocument(tuple))
+	if err != nil {
+		return "", err
+	}
+
+	return r.token()
+}
+
+// token encodes the current local wall-clock time (UTC) as a
+// consistency.Token; Mongo's cluster time is not consulted.
+func (r *RelationTupleRepository) token() (consistency.Token, error) {
+	now := time.Now().UTC()
+	return consistency.Encode(DriverName, clusterTimeRevision{value: now.Format(time.RFC3339Nano)}, now)
+}
+
+// decodeRelationTuples -
+func decodeRelationTuples(ctx context.Context, cursor *mongo.Cursor) ([]entities.RelationTuple, error) {
+	var documents []relationTupleDocument
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+
+	tuples := make([]entities.RelationTuple, 0, len(documents))
+	for _, doc := range documents {
+		tuples = append(tuples, entities.RelationTuple{
+			Namespace:       doc.Namespace,
+			ObjectID:        doc.ObjectID,
+			Relation:        doc.Relation,
+			UsersetEntity:   doc.UsersetEntity,
+			UsersetObjectID: doc.UsersetObjectID,
+			UsersetRelation: doc.UsersetRelation,
+		})
+	}
+
+	return tuples, nil
+}
+
+// toDocument -
+func toDocument(t entities.RelationTuple) relationTupleDocument {
+	return relationTupleDocument{
+		Namespace:       t.Namespace,
+		ObjectID:        t.ObjectID,
+		Relation:        t.Relation,
+		UsersetEntity:   t.UsersetEntity,
+		UsersetObjectID: t.UsersetObjectID,
+		UsersetRelation: t.UsersetRelation,
+	}
+}
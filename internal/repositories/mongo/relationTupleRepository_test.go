@@ -0,0 +1,153 @@
+//go:build integration
+
+package mongo_test
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	tcmongodb "github.com/testcontainers/testcontainers-go/modules/mongodb"
+
+	"github.com/Permify/permify/internal/entities"
+	mongorepo "github.com/Permify/permify/internal/repositories/mongo"
+)
+
+// newTestRepository spins up a disposable MongoDB container via
+// testcontainers-go so this test exercises the real driver without
+// requiring a live cluster in CI.
+func newTestRepository(t *testing.T) *mongorepo.RelationTupleRepository {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, err := tcmongodb.Run(ctx, "mongo:6")
+	if err != nil {
+		t.Fatalf("start mongo container: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("terminate mongo container: %v", err)
+		}
+	})
+
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("connection string: %v", err)
+	}
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := client.Disconnect(ctx); err != nil {
+			t.Fatalf("disconnect: %v", err)
+		}
+	})
+
+	repo := mongorepo.NewRelationTupleRepository(client.Database("permify_test"))
+	if err := repo.EnsureIndexes(ctx); err != nil {
+		t.Fatalf("ensure indexes: %v", err)
+	}
+
+	return repo
+}
+
+func TestRelationTupleRepository_QueryTuples(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+
+	want := entities.RelationTuple{
+		Namespace:       "document",
+		ObjectID:        "doc1",
+		Relation:        "viewer",
+		UsersetObjectID: "alice",
+	}
+
+	if _, err := repo.InsertTuple(ctx, want); err != nil {
+		t.Fatalf("insert tuple: %v", err)
+	}
+
+	got, err := repo.QueryTuples(ctx, "document", "doc1", "viewer")
+	if err != nil {
+		t.Fatalf("query tuples: %v", err)
+	}
+	if len(got) != 1 || got[0] != want {
+		t.Fatalf("expected [%+v], got %+v", want, got)
+	}
+}
+
+func TestRelationTupleRepository_QueryTuplesBySubject(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+
+	want := entities.RelationTuple{
+		Namespace:       "document",
+		ObjectID:        "doc1",
+		Relation:        "parent",
+		UsersetEntity:   "folder",
+		UsersetObjectID: "f1",
+		UsersetRelation: "...",
+	}
+
+	if _, err := repo.InsertTuple(ctx, want); err != nil {
+		t.Fatalf("insert tuple: %v", err)
+	}
+
+	got, err := repo.QueryTuplesBySubject(ctx, "folder", "f1", "...")
+	if err != nil {
+		t.Fatalf("query tuples by subject: %v", err)
+	}
+	if len(got) != 1 || got[0] != want {
+		t.Fatalf("expected [%+v], got %+v", want, got)
+	}
+}
+
+func TestRelationTupleRepository_QueryTuplesAt_RejectsPinnedReads(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+
+	token, err := repo.InsertTuple(ctx, entities.RelationTuple{
+		Namespace:       "document",
+		ObjectID:        "doc1",
+		Relation:        "viewer",
+		UsersetObjectID: "alice",
+	})
+	if err != nil {
+		t.Fatalf("insert tuple: %v", err)
+	}
+
+	if _, err := repo.QueryTuplesAt(ctx, "document", "doc1", "viewer", string(token)); err != mongorepo.ErrPointInTimeReadsNotSupported {
+		t.Fatalf("expected ErrPointInTimeReadsNotSupported, got %v", err)
+	}
+}
+
+func TestRelationTupleRepository_DeleteTuple(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepository(t)
+
+	tuple := entities.RelationTuple{
+		Namespace:       "document",
+		ObjectID:        "doc1",
+		Relation:        "viewer",
+		UsersetObjectID: "alice",
+	}
+
+	if _, err := repo.InsertTuple(ctx, tuple); err != nil {
+		t.Fatalf("insert tuple: %v", err)
+	}
+	if _, err := repo.DeleteTuple(ctx, tuple); err != nil {
+		t.Fatalf("delete tuple: %v", err)
+	}
+
+	got, err := repo.QueryTuples(ctx, "document", "doc1", "viewer")
+	if err != nil {
+		t.Fatalf("query tuples: %v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("expected no tuples after delete, got %+v", got)
+	}
+}
@@ -0,0 +1,19 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Permify/permify/internal/entities"
+)
+
+// IRelationTupleRepository -
+type IRelationTupleRepository interface {
+	QueryTuples(ctx context.Context, namespace string, objectID string, relation string) ([]entities.RelationTuple, error)
+	// QueryTuplesBySubject returns every tuple whose userset side matches the
+	// given subject, i.e. the reverse of QueryTuples. It powers LookupResources.
+	QueryTuplesBySubject(ctx context.Context, subjectNamespace string, subjectID string, subjectRelation string) ([]entities.RelationTuple, error)
+	// QueryTuplesAt behaves like QueryTuples but pins the read to revision, a
+	// value previously handed back by a write on this same backend. An empty
+	// revision means full consistency (read the latest state).
+	QueryTuplesAt(ctx context.Context, namespace string, objectID string, relation string, revision string) ([]entities.RelationTuple, error)
+}
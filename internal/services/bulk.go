@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/Permify/permify/internal/entities"
+	"github.com/Permify/permify/pkg/consistency"
+	"github.com/Permify/permify/pkg/tuple"
+)
+
+// CheckRequest is a single (subject, action, object) triple to evaluate as
+// part of a BulkCheck call.
+type CheckRequest struct {
+	Subject string
+	Action  string
+	Object  string
+	Depth   int
+	Token   string
+}
+
+// bulkState is threaded through a BulkCheck call via context so getUsers can
+// deduplicate identical (object, relation) subqueries across the batch.
+type bulkState struct {
+	group *singleflight.Group
+	sem   chan struct{}
+	// results keeps each successful query's rows for the rest of the call,
+	// so a repeat that arrives after the first has finished is not re-run.
+	results sync.Map
+}
+
+type bulkStateKey struct{}
+
+// withBulkState attaches state to ctx for the lifetime of one BulkCheck call.
+func withBulkState(ctx context.Context, state *bulkState) context.Context {
+	return context.WithValue(ctx, bulkStateKey{}, state)
+}
+
+// bulkStateFromContext -
+func bulkStateFromContext(ctx context.Context) (*bulkState, bool) {
+	state, ok := ctx.Value(bulkStateKey{}).(*bulkState)
+	return state, ok
+}
+
+// BulkCheck evaluates many (subject, action, object) triples in a single
+// call, returning decisions in the same order as reqs. Identical (object,
+// relation) subqueries - common when checking a list of resources for one
+// subject - fire against the repository at most once per call.
+func (service *PermissionService) BulkCheck(ctx context.Context, reqs []CheckRequest) ([]Decision, error) {
+	ctx = withBulkState(ctx, &bulkState{
+		group: &singleflight.Group{},
+		sem:   make(chan struct{}, service.bulkFanOut),
+	})
+
+	decisions := make([]Decision, len(reqs))
+
+	var wg sync.WaitGroup
+	wg.Add(len(reqs))
+
+	for i, req := range reqs {
+		go func(i int, req CheckRequest) {
+			defer wg.Done()
+			can, err := service.Check(ctx, req.Subject, req.Action, req.Object, req.Depth, req.Token)
+			decisions[i] = sendDecision(can, err)
+		}(i, req)
+	}
+
+	wg.Wait()
+
+	return decisions, nil
+}
+
+// queryTuples fetches the tuples for (object, relation), pinned to token if
+// non-empty. Within a BulkCheck call, identical queries are deduplicated via
+// a singleflight.Group and fan-out is capped by a semaphore; outside of one,
+// it queries the repository directly.
+func (service *PermissionService) queryTuples(ctx context.Context, object tuple.Object, relation tuple.Relation, token string) ([]entities.RelationTuple, error) {
+	query := func() ([]entities.RelationTuple, error) {
+		if token == "" {
+			return service.repository.QueryTuples(ctx, object.Namespace, object.ID, relation.String())
+		}
+
+		_, revision, _, err := consistency.Decode(consistency.Token(token))
+		if err != nil {
+			return nil, err
+		}
+
+		return service.repository.QueryTuplesAt(ctx, object.Namespace, object.ID, relation.String(), revision)
+	}
+
+	state, ok := bulkStateFromContext(ctx)
+	if !ok {
+		return query()
+	}
+
+	key := object.Namespace + ":" + object.ID + ":" + relation.String() + ":" + token
+
+	v, err, _ := state.group.Do(key, func() (interface{}, error) {
+		if cached, ok := state.results.Load(key); ok {
+			return cached, nil
+		}
+		state.sem <- struct{}{}
+		defer func() { <-state.sem }()
+		rows, err := query()
+		if err != nil {
+			return nil, err
+		}
+		state.results.Store(key, rows)
+		return rows, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return v.([]entities.RelationTuple), nil
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+
+	"github.com/Permify/permify/pkg/dsl/schema"
+	"github.com/Permify/permify/pkg/tuple"
+)
+
+// Node -
+type Node interface {
+	isNode()
+}
+
+// UnionNode -
+type UnionNode struct {
+	Children []Node
+}
+
+func (UnionNode) isNode() {}
+
+// IntersectionNode -
+type IntersectionNode struct {
+	Children []Node
+}
+
+func (IntersectionNode) isNode() {}
+
+// ExclusionNode -
+type ExclusionNode struct {
+	Children []Node
+}
+
+func (ExclusionNode) isNode() {}
+
+// LeafNode -
+type LeafNode struct {
+	Users            []tuple.User
+	IndirectUsersets []tuple.UserSet
+}
+
+func (LeafNode) isNode() {}
+
+// UsersetTree -
+type UsersetTree struct {
+	Root Node
+}
+
+// Flatten computes the access list implied by the tree: it unions,
+// intersects, or subtracts (for exclusion) each node's children according to
+// its rewrite type, rather than just concatenating every user mentioned.
+// A leaf contributes only its direct Users: members of its IndirectUsersets
+// are not resolved and do not appear in the result.
+func (t *UsersetTree) Flatten() []tuple.User {
+	if t == nil || t.Root == nil {
+		return nil
+	}
+	return flatten(t.Root)
+}
+
+// flatten -
+func flatten(node Node) []tuple.User {
+	switch n := node.(type) {
+	case LeafNode:
+		return n.Users
+	case UnionNode:
+		return unionUsers(n.Children)
+	case IntersectionNode:
+		return intersectUsers(n.Children)
+	case ExclusionNode:
+		return excludeUsers(n.Children)
+	default:
+		return nil
+	}
+}
+
+// unionUsers returns every distinct user found in any child.
+func unionUsers(children []Node) []tuple.User {
+	seen := make(map[tuple.User]struct{})
+	var users []tuple.User
+	for _, child := range children {
+		for _, u := range flatten(child) {
+			if _, ok := seen[u]; !ok {
+				seen[u] = struct{}{}
+				users = append(users, u)
+			}
+		}
+	}
+	return users
+}
+
+// intersectUsers returns only the users present in every child.
+func intersectUsers(children []Node) []tuple.User {
+	if len(children) == 0 {
+		return nil
+	}
+
+	counts := make(map[tuple.User]int)
+	for _, child := range children {
+		for _, u := range unionUsers([]Node{child}) {
+			counts[u]++
+		}
+	}
+
+	var users []tuple.User
+	for u, n := range counts {
+		if n == len(children) {
+			users = append(users, u)
+		}
+	}
+	return users
+}
+
+// excludeUsers returns the users in the first child minus every user found
+// in any of the rest, mirroring the difference rewrite's base-minus-
+// subtrahends semantics.
+func excludeUsers(children []Node) []tuple.User {
+	if len(children) == 0 {
+		return nil
+	}
+
+	excluded := make(map[tuple.User]struct{})
+	for _, child := range children[1:] {
+		for _, u := range flatten(child) {
+			excluded[u] = struct{}{}
+		}
+	}
+
+	var users []tuple.User
+	for _, u := range flatten(children[0]) {
+		if _, ok := excluded[u]; !ok {
+			users = append(users, u)
+		}
+	}
+	return users
+}
+
+// Expand builds the userset tree for an object+action, mirroring Check's schema
+// walk but returning the full tree instead of short-circuiting on a decision.
+func (service *PermissionService) Expand(ctx context.Context, o string, a string, d int) (*UsersetTree, error) {
+	object, err := tuple.ConvertObject(o)
+	if err != nil {
+		return nil, err
+	}
+
+	entity := service.schema.Entities[object.Namespace]
+
+	var child schema.Child
+
+	for _, act := range entity.Actions {
+		if act.Name == a {
+			child = act.Child
+			goto expand
+		}
+	}
+
+	if child == nil {
+		return nil, ActionCannotFoundError
+	}
+
+expand:
+
+	re := Request{
+		Object: object,
+		depth:  d,
+	}
+
+	root, err := service.expand(ctx, re, child)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UsersetTree{Root: root}, nil
+}
+
+// expand -
+func (service *PermissionService) expand(ctx context.Context, request Request, child schema.Child) (Node, error) {
+	switch child.GetKind() {
+	case schema.RewriteKind.String():
+		return service.expandRewrite(ctx, request, child.(schema.Rewrite))
+	case schema.LeafKind.String():
+		return service.expandLeaf(ctx, request, child.(schema.Leaf))
+	default:
+		return nil, UndefinedChildKindError
+	}
+}
+
+// expandRewrite -
+func (service *PermissionService) expandRewrite(ctx context.Context, request Request, child schema.Rewrite) (Node, error) {
+	children, err := service.expandChildren(ctx, request, child.Children)
+	if err != nil {
+		return nil, err
+	}
+
+	switch child.GetType() {
+	case schema.Union.String():
+		return UnionNode{Children: children}, nil
+	case schema.Intersection.String():
+		return IntersectionNode{Children: children}, nil
+	case schema.Difference.String():
+		return ExclusionNode{Children: children}, nil
+	default:
+		return nil, UndefinedChildTypeError
+	}
+}
+
+// expandChildren -
+func (service *PermissionService) expandChildren(ctx context.Context, request Request, children []schema.Child) ([]Node, error) {
+	nodes := make([]Node, 0, len(children))
+
+	for _, child := range children {
+		node, err := service.expand(ctx, request, child)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
+
+// expandLeaf enumerates the direct users and nested usersets for a single
+// relation. It is one-level: nested usersets are returned as IndirectUsersets
+// rather than recursed into, leaving composition up to the caller.
+func (service *PermissionService) expandLeaf(ctx context.Context, request Request, child schema.Leaf) (Node, error) {
+	switch child.GetType() {
+	case schema.TupleToUserSetType.String(), schema.ComputedUserSetType.String():
+		users, err := service.getUsers(ctx, request.Object, tuple.Relation(child.Value), request.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		var leaf LeafNode
+		for _, u := range users {
+			if u.IsUser() {
+				leaf.Users = append(leaf.Users, u)
+			} else {
+				leaf.IndirectUsersets = append(leaf.IndirectUsersets, u.UserSet)
+			}
+		}
+
+		return leaf, nil
+	default:
+		return nil, UndefinedChildTypeError
+	}
+}
@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+
+	"github.com/Permify/permify/internal/entities"
+	"github.com/Permify/permify/pkg/dsl/schema"
+	"github.com/Permify/permify/pkg/tuple"
+)
+
+// subjectKey identifies a (namespace, id, relation) triple that can be used
+// either as a concrete user or as a userset acting as a subject.
+type subjectKey struct {
+	namespace string
+	id        string
+	relation  string
+}
+
+// subjectParts splits a tuple.User into the subject triple QueryTuplesBySubject
+// expects. A concrete subject is stored as a plain row - an empty
+// UsersetEntity and UsersetRelation - mirroring the plain-user branch
+// getUsers reads on the forward path.
+func subjectParts(u tuple.User) (namespace string, id string, relation string) {
+	if u.UserSet.Object.ID != "" {
+		return u.UserSet.Object.Namespace, u.UserSet.Object.ID, u.UserSet.Relation.String()
+	}
+	return "", u.ID, ""
+}
+
+// wildcardSubjectKey is the subject identity a user:* tuple is stored under
+// (an empty UsersetEntity/UsersetRelation, per the wildcard branch in
+// getUsers). It shares the empty namespace subjectParts uses for a concrete
+// individual and differs from it only in its "*" ID.
+func wildcardSubjectKey() subjectKey {
+	return subjectKey{id: tuple.WildcardRelation}
+}
+
+// LookupResources returns the object IDs in namespace on which subject can
+// perform action, walking the same schema.Rewrite tree Check uses but in
+// reverse: from subject to objects instead of object to users.
+func (service *PermissionService) LookupResources(ctx context.Context, subject tuple.User, action string, namespace string, depth int) ([]tuple.Object, error) {
+	entity := service.schema.Entities[namespace]
+
+	var child schema.Child
+
+	for _, act := range entity.Actions {
+		if act.Name == action {
+			child = act.Child
+			goto lookup
+		}
+	}
+
+	if child == nil {
+		return nil, ActionCannotFoundError
+	}
+
+lookup:
+
+	re := Request{
+		Subject: subject,
+		depth:   depth,
+	}
+
+	ids, err := service.lookup(ctx, re, namespace, child)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]tuple.Object, 0, len(ids))
+	for id := range ids {
+		objects = append(objects, tuple.Object{Namespace: namespace, ID: id})
+	}
+
+	return objects, nil
+}
+
+// lookup -
+func (service *PermissionService) lookup(ctx context.Context, request Request, namespace string, child schema.Child) (map[string]struct{}, error) {
+	switch child.GetKind() {
+	case schema.RewriteKind.String():
+		return service.lookupRewrite(ctx, request, namespace, child.(schema.Rewrite))
+	case schema.LeafKind.String():
+		return service.lookupLeaf(ctx, request, namespace, child.(schema.Leaf))
+	default:
+		return nil, UndefinedChildKindError
+	}
+}
+
+// lookupRewrite -
+func (service *PermissionService) lookupRewrite(ctx context.Context, request Request, namespace string, child schema.Rewrite) (map[string]struct{}, error) {
+	sets := make([]map[string]struct{}, 0, len(child.Children))
+
+	for _, c := range child.Children {
+		s, err := service.lookup(ctx, request, namespace, c)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, s)
+	}
+
+	switch child.GetType() {
+	case schema.Union.String():
+		return unionSets(sets), nil
+	case schema.Intersection.String():
+		return intersectSets(sets), nil
+	case schema.Difference.String():
+		return differenceSets(sets), nil
+	default:
+		return nil, UndefinedChildTypeError
+	}
+}
+
+// lookupLeaf -
+func (service *PermissionService) lookupLeaf(ctx context.Context, request Request, namespace string, child schema.Leaf) (map[string]struct{}, error) {
+	switch child.GetType() {
+	case schema.TupleToUserSetType.String(), schema.ComputedUserSetType.String():
+		return service.lookupRelation(ctx, request, namespace, tuple.Relation(child.Value))
+	default:
+		return nil, UndefinedChildTypeError
+	}
+}
+
+// lookupRelation walks, breadth-first and bounded by request's depth, every
+// userset the subject is a member of, collecting object IDs granted
+// targetRelation directly or through an indirect group membership chain.
+func (service *PermissionService) lookupRelation(ctx context.Context, request Request, targetNamespace string, targetRelation tuple.Relation) (map[string]struct{}, error) {
+	r := targetRelation.Split()
+
+	start := subjectKey{}
+	start.namespace, start.id, start.relation = subjectParts(request.Subject)
+
+	visited := map[subjectKey]struct{}{start: {}}
+	queue := []subjectKey{start}
+
+	// A user:* wildcard tuple grants every concrete subject in that
+	// namespace, mirroring the wildcard branch in getUsers. Seed the walk
+	// with that universal identity too, so a resource granted only via
+	// wildcard - directly or through a further indirection - isn't missed
+	// just because it was never granted to this subject by name.
+	if request.Subject.IsUser() && !request.Subject.Wildcard {
+		wildcard := wildcardSubjectKey()
+		if _, ok := visited[wildcard]; !ok {
+			visited[wildcard] = struct{}{}
+			queue = append(queue, wildcard)
+		}
+	}
+
+	ids := make(map[string]struct{})
+
+	for len(queue) > 0 {
+		if request.isFinished() {
+			return nil, DepthError
+		}
+		request = request.decreased()
+
+		var next []subjectKey
+
+		for _, sk := range queue {
+			rows, err := service.queryTuplesBySubject(ctx, sk)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, row := range rows {
+				if sk == wildcardSubjectKey() {
+					def := service.schema.Entities[row.Namespace].Relations[row.Relation]
+					if schema.ValidateWildcardSubject(def, true) != nil {
+						continue
+					}
+				}
+
+				if row.Namespace == targetNamespace && row.Relation == r[0].String() {
+					ids[row.ObjectID] = struct{}{}
+					continue
+				}
+
+				nk := subjectKey{namespace: row.Namespace, id: row.ObjectID, relation: row.Relation}
+				if _, ok := visited[nk]; ok {
+					continue
+				}
+				visited[nk] = struct{}{}
+				next = append(next, nk)
+			}
+		}
+
+		queue = next
+	}
+
+	return ids, nil
+}
+
+// queryTuplesBySubject looks up the tuples granting sk, matching both its
+// exact relation and the ELLIPSIS form. A tupleset-style reference (e.g.
+// "document#parent@folder:f1") is stored with UsersetRelation = ELLIPSIS;
+// the concrete relation it forwards to is only resolved per-leaf via the
+// r[0]/r[1] split getUsers does on the forward Check path, which the
+// reverse walk can't reconstruct without re-parsing every schema along the
+// way. Matching both forms catches those references instead of missing them.
+func (service *PermissionService) queryTuplesBySubject(ctx context.Context, sk subjectKey) ([]entities.RelationTuple, error) {
+	rows, err := service.repository.QueryTuplesBySubject(ctx, sk.namespace, sk.id, sk.relation)
+	if err != nil {
+		return nil, err
+	}
+
+	if sk.relation == tuple.ELLIPSIS {
+		return rows, nil
+	}
+
+	ellipsisRows, err := service.repository.QueryTuplesBySubject(ctx, sk.namespace, sk.id, tuple.ELLIPSIS)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(rows, ellipsisRows...), nil
+}
+
+// unionSets -
+func unionSets(sets []map[string]struct{}) map[string]struct{} {
+	result := make(map[string]struct{})
+	for _, s := range sets {
+		for id := range s {
+			result[id] = struct{}{}
+		}
+	}
+	return result
+}
+
+// intersectSets -
+func intersectSets(sets []map[string]struct{}) map[string]struct{} {
+	result := make(map[string]struct{})
+	if len(sets) == 0 {
+		return result
+	}
+
+	for id := range sets[0] {
+		inAll := true
+		for _, s := range sets[1:] {
+			if _, ok := s[id]; !ok {
+				inAll = false
+				break
+			}
+		}
+		if inAll {
+			result[id] = struct{}{}
+		}
+	}
+
+	return result
+}
+
+// differenceSets -
+func differenceSets(sets []map[string]struct{}) map[string]struct{} {
+	result := make(map[string]struct{})
+	if len(sets) == 0 {
+		return result
+	}
+
+	for id := range sets[0] {
+		excluded := false
+		for _, s := range sets[1:] {
+			if _, ok := s[id]; ok {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			result[id] = struct{}{}
+		}
+	}
+
+	return result
+}
@@ -3,8 +3,8 @@ package services
 import (
 	"context"
 	"errors"
+	"time"
 
-	"github.com/Permify/permify/internal/entities"
 	"github.com/Permify/permify/internal/repositories"
 	"github.com/Permify/permify/pkg/dsl/schema"
 	"github.com/Permify/permify/pkg/tuple"
@@ -32,62 +32,117 @@ func sendDecision(can bool, err error) Decision {
 	}
 }
 
+// Trace records why a single rewrite/leaf node produced its decision, so a
+// Check call can be explained after the fact via CheckWithTrace.
+type Trace struct {
+	Kind     string
+	Object   tuple.Object
+	Relation tuple.Relation
+	Users    []tuple.User
+	Decision bool
+	Duration time.Duration
+	Children []*Trace
+}
+
+// newTrace -
+func newTrace(kind string, decision bool, start time.Time, children []*Trace) *Trace {
+	return &Trace{
+		Kind:     kind,
+		Decision: decision,
+		Duration: time.Since(start),
+		Children: children,
+	}
+}
+
+// checkResult pairs a Decision with the Trace node that produced it.
+type checkResult struct {
+	Decision Decision
+	Trace    *Trace
+}
+
 // CheckFunction -
-type CheckFunction func(ctx context.Context, decisionChan chan<- Decision)
+type CheckFunction func(ctx context.Context) checkResult
 
 // Combiner .
-type Combiner func(ctx context.Context, requests []CheckFunction) Decision
+type Combiner func(ctx context.Context, kind string, functions []CheckFunction) checkResult
 
 // IPermissionService -
 type IPermissionService interface {
-	Check(ctx context.Context, s string, a string, o string, d int) (bool, error)
+	Check(ctx context.Context, s string, a string, o string, d int, token string) (bool, error)
+	CheckWithTrace(ctx context.Context, s string, a string, o string, d int, token string) (bool, *Trace, error)
+	Expand(ctx context.Context, o string, a string, d int) (*UsersetTree, error)
+	LookupResources(ctx context.Context, subject tuple.User, action string, namespace string, depth int) ([]tuple.Object, error)
+	BulkCheck(ctx context.Context, reqs []CheckRequest) ([]Decision, error)
 }
 
+// defaultBulkFanOut is used when NewPermissionService is given a
+// non-positive bulkFanOut.
+const defaultBulkFanOut = 50
+
 // PermissionService -
 type PermissionService struct {
 	repository repositories.IRelationTupleRepository
 	schema     schema.Schema
+	// bulkFanOut caps how many repository queries a single BulkCheck call
+	// may have in flight at once, protecting the datastore from a large
+	// batch.
+	bulkFanOut int
 }
 
-// NewPermissionService -
-func NewPermissionService(repo repositories.IRelationTupleRepository, schema schema.Schema) *PermissionService {
+// NewPermissionService builds a PermissionService. bulkFanOut configures the
+// BulkCheck concurrency cap described above; a non-positive value falls back
+// to defaultBulkFanOut.
+func NewPermissionService(repo repositories.IRelationTupleRepository, schema schema.Schema, bulkFanOut int) *PermissionService {
+	if bulkFanOut <= 0 {
+		bulkFanOut = defaultBulkFanOut
+	}
+
 	return &PermissionService{
 		repository: repo,
 		schema:     schema,
+		bulkFanOut: bulkFanOut,
 	}
 }
 
-// Request -
+// Request is an immutable, value-typed snapshot of a single Check call.
+// depth is decremented by value on each recursive step so the concurrent
+// sibling branches fanned out by union/intersection/difference never share
+// mutable state.
 type Request struct {
 	Object  tuple.Object
 	Subject tuple.User
-	depth   *int
-}
-
-// SetDepth -
-func (r *Request) SetDepth(i int) {
-	r.depth = &i
+	// Token pins reads to a known revision (a "snap token"). Empty means full
+	// consistency: read the latest state, today's behavior.
+	Token string
+	depth int
 }
 
-// decrease -
-func (r *Request) decrease() *Request {
-	*r.depth--
+// decreased returns a copy of r one level deeper into the recursion.
+func (r Request) decreased() Request {
+	r.depth--
 	return r
 }
 
-// isFinish -
-func (r *Request) isFinish() bool {
-	return *r.depth <= 0
+// isFinished reports whether r has run out of depth budget.
+func (r Request) isFinished() bool {
+	return r.depth <= 0
 }
 
 // Check -
-func (service *PermissionService) Check(ctx context.Context, s string, a string, o string, d int) (can bool, err error) {
-	can = false
+// token pins the read to a revision returned by a prior write (a "snap
+// token"); an empty token means full consistency, the previous behavior.
+func (service *PermissionService) Check(ctx context.Context, s string, a string, o string, d int, token string) (bool, error) {
+	can, _, err := service.CheckWithTrace(ctx, s, a, o, d, token)
+	return can, err
+}
 
-	var object tuple.Object
-	object, err = tuple.ConvertObject(o)
+// CheckWithTrace behaves like Check but also returns the Trace explaining the
+// decision: the rewrite/leaf kind, object+relation queried, users returned,
+// decision, and elapsed time at every node walked.
+func (service *PermissionService) CheckWithTrace(ctx context.Context, s string, a string, o string, d int, token string) (bool, *Trace, error) {
+	object, err := tuple.ConvertObject(o)
 	if err != nil {
-		return false, err
+		return false, nil, err
 	}
 
 	entity := service.schema.Entities[object.Namespace]
@@ -102,7 +157,7 @@ func (service *PermissionService) Check(ctx context.Context, s string, a string,
 	}
 
 	if child == nil {
-		return false, ActionCannotFoundError
+		return false, nil, ActionCannotFoundError
 	}
 
 check:
@@ -110,14 +165,17 @@ check:
 	re := Request{
 		Object:  object,
 		Subject: tuple.ConvertUser(s),
+		Token:   token,
+		depth:   d,
 	}
-	re.SetDepth(d)
 
-	return service.c(ctx, &re, child)
+	res := service.c(ctx, re, child)
+
+	return res.Decision.Can, res.Trace, res.Decision.Err
 }
 
 // c -
-func (service *PermissionService) c(ctx context.Context, request *Request, child schema.Child) (bool, error) {
+func (service *PermissionService) c(ctx context.Context, request Request, child schema.Child) checkResult {
 	var fn CheckFunction
 
 	switch child.GetKind() {
@@ -128,28 +186,28 @@ func (service *PermissionService) c(ctx context.Context, request *Request, child
 	}
 
 	if fn == nil {
-		return false, UndefinedChildKindError
+		return checkResult{Decision: sendDecision(false, UndefinedChildKindError)}
 	}
 
-	result := union(ctx, []CheckFunction{fn})
-
-	return result.Can, result.Err
+	return fn(ctx)
 }
 
 // checkRewrite -
-func (service *PermissionService) checkRewrite(ctx context.Context, request *Request, child schema.Rewrite) CheckFunction {
+func (service *PermissionService) checkRewrite(ctx context.Context, request Request, child schema.Rewrite) CheckFunction {
 	switch child.GetType() {
 	case schema.Union.String():
-		return service.set(ctx, request, child.Children, union)
+		return service.set(ctx, request, child.Children, schema.Union.String(), union)
 	case schema.Intersection.String():
-		return service.set(ctx, request, child.Children, intersection)
+		return service.set(ctx, request, child.Children, schema.Intersection.String(), intersection)
+	case schema.Difference.String():
+		return service.set(ctx, request, child.Children, schema.Difference.String(), difference)
 	default:
 		return fail(UndefinedChildTypeError)
 	}
 }
 
 // checkLeaf -
-func (service *PermissionService) checkLeaf(ctx context.Context, request *Request, child schema.Leaf) CheckFunction {
+func (service *PermissionService) checkLeaf(ctx context.Context, request Request, child schema.Leaf) CheckFunction {
 	switch child.GetType() {
 	case schema.TupleToUserSetType.String():
 		return service.check(ctx, request.Object, tuple.Relation(child.Value), request)
@@ -161,7 +219,7 @@ func (service *PermissionService) checkLeaf(ctx context.Context, request *Reques
 }
 
 // set -
-func (service *PermissionService) set(ctx context.Context, request *Request, children []schema.Child, combiner Combiner) CheckFunction {
+func (service *PermissionService) set(ctx context.Context, request Request, children []schema.Child, kind string, combiner Combiner) CheckFunction {
 	var functions []CheckFunction
 
 	for _, child := range children {
@@ -175,80 +233,151 @@ func (service *PermissionService) set(ctx context.Context, request *Request, chi
 		}
 	}
 
-	return func(ctx context.Context, resultChan chan<- Decision) {
-		resultChan <- combiner(ctx, functions)
+	return func(ctx context.Context) checkResult {
+		return combiner(ctx, kind, functions)
 	}
 }
 
 // union -
-func union(ctx context.Context, functions []CheckFunction) Decision {
+func union(ctx context.Context, kind string, functions []CheckFunction) checkResult {
 	if len(functions) == 0 {
-		return sendDecision(false, nil)
+		return checkResult{Decision: sendDecision(false, nil)}
 	}
 
-	decisionChan := make(chan Decision, len(functions))
+	start := time.Now()
+	resultChan := make(chan checkResult, len(functions))
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	for _, fn := range functions {
-		go fn(childCtx, decisionChan)
+		go func(fn CheckFunction) {
+			resultChan <- fn(childCtx)
+		}(fn)
 	}
 
+	var children []*Trace
+
 	for i := 0; i < len(functions); i++ {
 		select {
-		case result := <-decisionChan:
-			if result.Err == nil && result.Can {
-				return sendDecision(true, nil)
+		case res := <-resultChan:
+			children = append(children, res.Trace)
+			if res.Decision.Err == nil && res.Decision.Can {
+				return checkResult{Decision: sendDecision(true, nil), Trace: newTrace(kind, true, start, children)}
 			}
-			if result.Err != nil {
-				return sendDecision(false, result.Err)
+			if res.Decision.Err != nil {
+				return checkResult{Decision: sendDecision(false, res.Decision.Err), Trace: newTrace(kind, false, start, children)}
 			}
 		case <-ctx.Done():
-			return sendDecision(false, CanceledError)
+			return checkResult{Decision: sendDecision(false, CanceledError)}
 		}
 	}
 
-	return sendDecision(false, nil)
+	return checkResult{Decision: sendDecision(false, nil), Trace: newTrace(kind, false, start, children)}
 }
 
 // intersection -
-func intersection(ctx context.Context, functions []CheckFunction) Decision {
+func intersection(ctx context.Context, kind string, functions []CheckFunction) checkResult {
 	if len(functions) == 0 {
-		return sendDecision(false, nil)
+		return checkResult{Decision: sendDecision(false, nil)}
 	}
 
-	decisionChan := make(chan Decision, len(functions))
+	start := time.Now()
+	resultChan := make(chan checkResult, len(functions))
 	childCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	for _, fn := range functions {
-		go fn(childCtx, decisionChan)
+		go func(fn CheckFunction) {
+			resultChan <- fn(childCtx)
+		}(fn)
 	}
 
+	var children []*Trace
+
 	for i := 0; i < len(functions); i++ {
 		select {
-		case result := <-decisionChan:
-			if result.Err == nil && !result.Can {
-				return sendDecision(false, nil)
+		case res := <-resultChan:
+			children = append(children, res.Trace)
+			if res.Decision.Err == nil && !res.Decision.Can {
+				return checkResult{Decision: sendDecision(false, nil), Trace: newTrace(kind, false, start, children)}
+			}
+			if res.Decision.Err != nil {
+				return checkResult{Decision: sendDecision(false, res.Decision.Err), Trace: newTrace(kind, false, start, children)}
+			}
+		case <-ctx.Done():
+			return checkResult{Decision: sendDecision(false, CanceledError)}
+		}
+	}
+
+	return checkResult{Decision: sendDecision(true, nil), Trace: newTrace(kind, true, start, children)}
+}
+
+// difference -
+// The first function is the base set; every other function is a subtrahend.
+// The result is true iff the base returns true and none of the subtrahends do.
+func difference(ctx context.Context, kind string, functions []CheckFunction) checkResult {
+	if len(functions) == 0 {
+		return checkResult{Decision: sendDecision(false, nil)}
+	}
+
+	start := time.Now()
+	base := functions[0]
+	subtrahends := functions[1:]
+
+	baseChan := make(chan checkResult, 1)
+	subChan := make(chan checkResult, len(subtrahends))
+	childCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() { baseChan <- base(childCtx) }()
+	for _, fn := range subtrahends {
+		go func(fn CheckFunction) { subChan <- fn(childCtx) }(fn)
+	}
+
+	var children []*Trace
+	baseDecided := false
+	remaining := len(subtrahends)
+
+	for !baseDecided || remaining > 0 {
+		select {
+		case res := <-baseChan:
+			children = append(children, res.Trace)
+			if res.Decision.Err != nil {
+				return checkResult{Decision: sendDecision(false, res.Decision.Err), Trace: newTrace(kind, false, start, children)}
 			}
-			if result.Err != nil {
-				return sendDecision(false, result.Err)
+			if !res.Decision.Can {
+				return checkResult{Decision: sendDecision(false, nil), Trace: newTrace(kind, false, start, children)}
+			}
+			baseDecided = true
+		case res := <-subChan:
+			remaining--
+			children = append(children, res.Trace)
+			if res.Decision.Err != nil {
+				return checkResult{Decision: sendDecision(false, res.Decision.Err), Trace: newTrace(kind, false, start, children)}
+			}
+			if res.Decision.Can {
+				return checkResult{Decision: sendDecision(false, nil), Trace: newTrace(kind, false, start, children)}
 			}
 		case <-ctx.Done():
-			return sendDecision(false, CanceledError)
+			return checkResult{Decision: sendDecision(false, CanceledError)}
 		}
 	}
 
-	return sendDecision(true, nil)
+	return checkResult{Decision: sendDecision(true, nil), Trace: newTrace(kind, true, start, children)}
 }
 
 // getUsers -
-func (service *PermissionService) getUsers(ctx context.Context, object tuple.Object, relation tuple.Relation) (users []tuple.User, err error) {
+// token, if non-empty, pins the read to the revision it encodes rather than
+// the latest state. A wildcard (user:*) row is validated against the
+// relation's schema.RelationDefinition before being surfaced; one that
+// fails validation is dropped rather than failing the whole call, so it
+// doesn't break other subjects' unrelated direct grants on the same
+// object+relation.
+func (service *PermissionService) getUsers(ctx context.Context, object tuple.Object, relation tuple.Relation, token string) (users []tuple.User, err error) {
 
 	r := relation.Split()
 
-	var en []entities.RelationTuple
-	en, err = service.repository.QueryTuples(ctx, object.Namespace, object.ID, r[0].String())
+	en, err := service.queryTuples(ctx, object, r[0], token)
 	if err != nil {
 		return nil, err
 	}
@@ -271,6 +400,19 @@ func (service *PermissionService) getUsers(ctx context.Context, object tuple.Obj
 			}
 
 			users = append(users, user)
+		} else if entity.UsersetObjectID == tuple.WildcardRelation {
+			def := service.schema.Entities[object.Namespace].Relations[r[0].String()]
+			if err := schema.ValidateWildcardSubject(def, true); err != nil {
+				// A relation no longer allowing wildcards shouldn't break
+				// every other subject's direct grant on the same
+				// object+relation; just drop this one invalid row.
+				continue
+			}
+
+			users = append(users, tuple.User{
+				Wildcard:  true,
+				Namespace: "user",
+			})
 		} else {
 			users = append(users, tuple.User{
 				ID: entity.UsersetObjectID,
@@ -282,43 +424,50 @@ func (service *PermissionService) getUsers(ctx context.Context, object tuple.Obj
 }
 
 // check -
-func (service *PermissionService) check(ctx context.Context, object tuple.Object, relation tuple.Relation, re *Request) CheckFunction {
-	return func(ctx context.Context, decisionChan chan<- Decision) {
-		var err error
+func (service *PermissionService) check(ctx context.Context, object tuple.Object, relation tuple.Relation, re Request) CheckFunction {
+	return func(ctx context.Context) checkResult {
+		start := time.Now()
 
-		if re.isFinish() {
-			decisionChan <- sendDecision(false, DepthError)
+		if re.isFinished() {
+			return checkResult{Decision: sendDecision(false, DepthError)}
 		}
 
-		var users []tuple.User
-		users, err = service.getUsers(ctx, object, relation)
-
+		users, err := service.getUsers(ctx, object, relation, re.Token)
 		if err != nil {
-			fail(err)
-			return
+			return checkResult{Decision: sendDecision(false, err)}
+		}
+
+		trace := &Trace{
+			Kind:     "leaf",
+			Object:   object,
+			Relation: relation,
+			Users:    users,
 		}
 
 		for _, t := range users {
 			if t.Equals(re.Subject) {
-				decisionChan <- sendDecision(true, err)
-				return
-			} else {
-				if !t.IsUser() {
-					re.decrease()
-					decisionChan <- union(ctx, []CheckFunction{service.check(ctx, t.UserSet.Object, t.UserSet.Relation, re)})
-					return
-				}
+				trace.Decision = true
+				trace.Duration = time.Since(start)
+				return checkResult{Decision: sendDecision(true, nil), Trace: trace}
+			}
+
+			if !t.IsUser() {
+				nested := union(ctx, "union", []CheckFunction{service.check(ctx, t.UserSet.Object, t.UserSet.Relation, re.decreased())})
+				trace.Children = append(trace.Children, nested.Trace)
+				trace.Decision = nested.Decision.Err == nil && nested.Decision.Can
+				trace.Duration = time.Since(start)
+				return checkResult{Decision: nested.Decision, Trace: trace}
 			}
 		}
 
-		decisionChan <- sendDecision(false, err)
-		return
+		trace.Duration = time.Since(start)
+		return checkResult{Decision: sendDecision(false, nil), Trace: trace}
 	}
 }
 
 // fail -
 func fail(err error) CheckFunction {
-	return func(ctx context.Context, decisionChan chan<- Decision) {
-		decisionChan <- sendDecision(false, err)
+	return func(ctx context.Context) checkResult {
+		return checkResult{Decision: sendDecision(false, err)}
 	}
 }
@@ -0,0 +1,337 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Permify/permify/internal/entities"
+	"github.com/Permify/permify/pkg/dsl/schema"
+)
+
+// fakeTupleRepository is an in-memory repositories.IRelationTupleRepository
+// for exercising PermissionService without a real datastore.
+type fakeTupleRepository struct {
+	tuples []entities.RelationTuple
+}
+
+func (f *fakeTupleRepository) QueryTuples(ctx context.Context, namespace string, objectID string, relation string) ([]entities.RelationTuple, error) {
+	var out []entities.RelationTuple
+	for _, t := range f.tuples {
+		if t.Namespace == namespace && t.ObjectID == objectID && t.Relation == relation {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeTupleRepository) QueryTuplesBySubject(ctx context.Context, subjectNamespace string, subjectID string, subjectRelation string) ([]entities.RelationTuple, error) {
+	var out []entities.RelationTuple
+	for _, t := range f.tuples {
+		if t.UsersetEntity == subjectNamespace && t.UsersetObjectID == subjectID && t.UsersetRelation == subjectRelation {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeTupleRepository) QueryTuplesAt(ctx context.Context, namespace string, objectID string, relation string, revision string) ([]entities.RelationTuple, error) {
+	return f.QueryTuples(ctx, namespace, objectID, relation)
+}
+
+// TestCheck_WildcardDirect covers a wildcard subject matched directly on the
+// checked object's own relation, both allowed and dropped by the schema.
+func TestCheck_WildcardDirect(t *testing.T) {
+	newService := func(allowWildcard bool) *PermissionService {
+		repo := &fakeTupleRepository{
+			tuples: []entities.RelationTuple{
+				{Namespace: "document", ObjectID: "doc1", Relation: "viewer", UsersetObjectID: "*"},
+			},
+		}
+
+		s := schema.Schema{
+			Entities: map[string]schema.Entity{
+				"document": {
+					Name: "document",
+					Actions: []schema.Action{
+						{Name: "view", Child: schema.Leaf{Type: schema.ComputedUserSetType, Value: "viewer"}},
+					},
+					Relations: map[string]schema.RelationDefinition{
+						"viewer": {Name: "viewer", AllowWildcard: allowWildcard},
+					},
+				},
+			},
+		}
+
+		return NewPermissionService(repo, s, 0)
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		service := newService(true)
+
+		can, err := service.Check(context.Background(), "user:alice", "view", "document:doc1", 10, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !can {
+			t.Fatal("expected alice to be granted view via the user:* wildcard")
+		}
+	})
+
+	t.Run("dropped", func(t *testing.T) {
+		service := newService(false)
+
+		can, err := service.Check(context.Background(), "user:alice", "view", "document:doc1", 10, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if can {
+			t.Fatal("expected check to fail closed when the relation disallows wildcards")
+		}
+	})
+}
+
+// TestCheck_WildcardRejectionDoesNotBreakSiblingGrant ensures a wildcard row
+// the schema rejects is dropped on its own, rather than failing the whole
+// getUsers call for every other subject querying the same object+relation.
+func TestCheck_WildcardRejectionDoesNotBreakSiblingGrant(t *testing.T) {
+	repo := &fakeTupleRepository{
+		tuples: []entities.RelationTuple{
+			{Namespace: "document", ObjectID: "doc1", Relation: "viewer", UsersetObjectID: "*"},
+			{Namespace: "document", ObjectID: "doc1", Relation: "viewer", UsersetObjectID: "bob"},
+		},
+	}
+
+	s := schema.Schema{
+		Entities: map[string]schema.Entity{
+			"document": {
+				Name: "document",
+				Actions: []schema.Action{
+					{Name: "view", Child: schema.Leaf{Type: schema.ComputedUserSetType, Value: "viewer"}},
+				},
+				Relations: map[string]schema.RelationDefinition{
+					"viewer": {Name: "viewer", AllowWildcard: false},
+				},
+			},
+		},
+	}
+
+	service := NewPermissionService(repo, s, 0)
+
+	can, err := service.Check(context.Background(), "user:bob", "view", "document:doc1", 10, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !can {
+		t.Fatal("expected bob's direct grant to survive the sibling wildcard row being rejected")
+	}
+}
+
+// TestCheck_Difference covers the difference (exclusion) rewrite: true iff
+// the base relation grants access and none of the subtrahend relations do.
+func TestCheck_Difference(t *testing.T) {
+	repo := &fakeTupleRepository{
+		tuples: []entities.RelationTuple{
+			{Namespace: "document", ObjectID: "doc1", Relation: "editor", UsersetObjectID: "alice"},
+			{Namespace: "document", ObjectID: "doc1", Relation: "banned", UsersetObjectID: "alice"},
+			{Namespace: "document", ObjectID: "doc2", Relation: "editor", UsersetObjectID: "alice"},
+		},
+	}
+
+	s := schema.Schema{
+		Entities: map[string]schema.Entity{
+			"document": {
+				Name: "document",
+				Actions: []schema.Action{
+					{
+						Name: "edit",
+						Child: schema.Rewrite{
+							Type: schema.Difference,
+							Children: []schema.Child{
+								schema.Leaf{Type: schema.ComputedUserSetType, Value: "editor"},
+								schema.Leaf{Type: schema.ComputedUserSetType, Value: "banned"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	service := NewPermissionService(repo, s, 0)
+
+	can, err := service.Check(context.Background(), "user:alice", "edit", "document:doc1", 10, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if can {
+		t.Fatal("expected alice to be denied edit on doc1: banned subtracts the editor grant")
+	}
+
+	can, err = service.Check(context.Background(), "user:alice", "edit", "document:doc2", 10, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !can {
+		t.Fatal("expected alice to be granted edit on doc2: editor grant with no banned row")
+	}
+}
+
+// TestCheck_DepthNotSharedAcrossIntersectionBranches guards the bug
+// Request's value-typed, immutable depth was introduced to close: depth used
+// to be threaded as a shared *int, so decrementing it in one concurrently
+// running rewrite branch could corrupt a sibling branch's view of its own
+// remaining budget. Both children here need exactly one level of recursion
+// off a shared starting depth; if depth were ever shared again, this would
+// flake with a spurious DepthError.
+func TestCheck_DepthNotSharedAcrossIntersectionBranches(t *testing.T) {
+	repo := &fakeTupleRepository{
+		tuples: []entities.RelationTuple{
+			{Namespace: "document", ObjectID: "doc1", Relation: "a", UsersetEntity: "groupA", UsersetObjectID: "g1", UsersetRelation: "..."},
+			{Namespace: "document", ObjectID: "doc1", Relation: "b", UsersetEntity: "groupB", UsersetObjectID: "g2", UsersetRelation: "..."},
+			{Namespace: "groupA", ObjectID: "g1", Relation: "viewer", UsersetObjectID: "alice"},
+			{Namespace: "groupB", ObjectID: "g2", Relation: "viewer", UsersetObjectID: "alice"},
+		},
+	}
+
+	s := schema.Schema{
+		Entities: map[string]schema.Entity{
+			"document": {
+				Name: "document",
+				Actions: []schema.Action{
+					{
+						Name: "view",
+						Child: schema.Rewrite{
+							Type: schema.Intersection,
+							Children: []schema.Child{
+								schema.Leaf{Type: schema.TupleToUserSetType, Value: "a.viewer"},
+								schema.Leaf{Type: schema.TupleToUserSetType, Value: "b.viewer"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	service := NewPermissionService(repo, s, 0)
+
+	for i := 0; i < 50; i++ {
+		can, err := service.Check(context.Background(), "user:alice", "view", "document:doc1", 2, "")
+		if err != nil {
+			t.Fatalf("unexpected error on iteration %d: %v", i, err)
+		}
+		if !can {
+			t.Fatalf("expected alice to be granted view on iteration %d: both intersection branches should resolve within the shared depth budget", i)
+		}
+	}
+}
+
+// TestCheckWithTrace covers that the returned Trace explains the decision:
+// a leaf node naming the object/relation queried, the users found, and
+// matching the top-level decision.
+func TestCheckWithTrace(t *testing.T) {
+	repo := &fakeTupleRepository{
+		tuples: []entities.RelationTuple{
+			{Namespace: "document", ObjectID: "doc1", Relation: "viewer", UsersetObjectID: "alice"},
+		},
+	}
+
+	s := schema.Schema{
+		Entities: map[string]schema.Entity{
+			"document": {
+				Name: "document",
+				Actions: []schema.Action{
+					{Name: "view", Child: schema.Leaf{Type: schema.ComputedUserSetType, Value: "viewer"}},
+				},
+			},
+		},
+	}
+
+	service := NewPermissionService(repo, s, 0)
+
+	can, trace, err := service.CheckWithTrace(context.Background(), "user:alice", "view", "document:doc1", 10, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !can {
+		t.Fatal("expected alice to be granted view")
+	}
+	if trace == nil {
+		t.Fatal("expected a non-nil trace")
+	}
+	if !trace.Decision {
+		t.Fatal("expected the trace's decision to match the granted check")
+	}
+	if trace.Object.Namespace != "document" || trace.Object.ID != "doc1" {
+		t.Fatalf("expected trace to record the queried object, got %+v", trace.Object)
+	}
+	if trace.Relation.String() != "viewer" {
+		t.Fatalf("expected trace to record the queried relation, got %q", trace.Relation)
+	}
+	found := false
+	for _, u := range trace.Users {
+		if u.ID == "alice" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected trace to record alice among the queried users, got %+v", trace.Users)
+	}
+}
+
+// TestCheck_WildcardNestedUserset covers a wildcard subject reached through a
+// tuple-to-userset indirection (document -> parent folder -> viewer), both
+// allowed and rejected by the referenced folder's schema.
+func TestCheck_WildcardNestedUserset(t *testing.T) {
+	newService := func(allowWildcard bool) *PermissionService {
+		repo := &fakeTupleRepository{
+			tuples: []entities.RelationTuple{
+				{Namespace: "document", ObjectID: "doc1", Relation: "parent", UsersetEntity: "folder", UsersetObjectID: "f1", UsersetRelation: "..."},
+				{Namespace: "folder", ObjectID: "f1", Relation: "viewer", UsersetObjectID: "*"},
+			},
+		}
+
+		s := schema.Schema{
+			Entities: map[string]schema.Entity{
+				"document": {
+					Name: "document",
+					Actions: []schema.Action{
+						{Name: "view", Child: schema.Leaf{Type: schema.TupleToUserSetType, Value: "parent.viewer"}},
+					},
+				},
+				"folder": {
+					Name: "folder",
+					Relations: map[string]schema.RelationDefinition{
+						"viewer": {Name: "viewer", AllowWildcard: allowWildcard},
+					},
+				},
+			},
+		}
+
+		return NewPermissionService(repo, s, 0)
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		service := newService(true)
+
+		can, err := service.Check(context.Background(), "user:alice", "view", "document:doc1", 10, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !can {
+			t.Fatal("expected alice to be granted view via the folder's user:* wildcard")
+		}
+	})
+
+	t.Run("dropped", func(t *testing.T) {
+		service := newService(false)
+
+		can, err := service.Check(context.Background(), "user:alice", "view", "document:doc1", 10, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if can {
+			t.Fatal("expected check to fail closed when the folder's relation disallows wildcards")
+		}
+	})
+}
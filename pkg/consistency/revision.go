@@ -0,0 +1,8 @@
+package consistency
+
+// Revision is a storage engine's monotonically-increasing position, e.g. a
+// Postgres LSN or a Mongo cluster time. Each repository backend implements
+// its own Revision and is responsible for producing and comparing it.
+type Revision interface {
+	String() string
+}
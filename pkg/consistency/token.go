@@ -0,0 +1,145 @@
+package consistency
+
+import (
+	"encoding/base64"
+	"errors"
+	"time"
+)
+
+// Token is the opaque value a write returns and a later Check can present to
+// pin its reads to that write's revision, closing the Zanzibar "new enemy"
+// problem. An empty Token means full consistency, i.e. today's behavior.
+//
+// A Token is a base64-encoded protobuf message of three fields: driver
+// (string, field 1), revision (string, field 2), and timestamp (int64 unix
+// seconds, field 3). It's encoded and decoded by hand below rather than via
+// a generated .pb.go, since there's no protoc toolchain in this build; the
+// wire format is the standard protobuf one, so a real .proto/generated
+// client can read it unchanged.
+type Token string
+
+const (
+	driverField    = 1
+	revisionField  = 2
+	timestampField = 3
+
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+// Encode produces a Token for the given driver and revision.
+func Encode(driver string, revision Revision, at time.Time) (Token, error) {
+	var raw []byte
+	raw = appendStringField(raw, driverField, driver)
+	raw = appendStringField(raw, revisionField, revision.String())
+	raw = appendVarintField(raw, timestampField, uint64(at.Unix()))
+
+	return Token(base64.StdEncoding.EncodeToString(raw)), nil
+}
+
+// Decode recovers the driver, revision, and write time encoded in a Token.
+// An empty Token decodes to the zero values and a nil error.
+func Decode(token Token) (driver string, revision string, at time.Time, err error) {
+	if token == "" {
+		return "", "", time.Time{}, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(string(token))
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	var timestamp uint64
+
+	for len(raw) > 0 {
+		var tag uint64
+		tag, raw, err = readVarint(raw)
+		if err != nil {
+			return "", "", time.Time{}, err
+		}
+
+		field := tag >> 3
+		wireType := tag & 0x7
+
+		switch wireType {
+		case wireBytes:
+			var value string
+			value, raw, err = readString(raw)
+			if err != nil {
+				return "", "", time.Time{}, err
+			}
+			switch field {
+			case driverField:
+				driver = value
+			case revisionField:
+				revision = value
+			}
+		case wireVarint:
+			var value uint64
+			value, raw, err = readVarint(raw)
+			if err != nil {
+				return "", "", time.Time{}, err
+			}
+			if field == timestampField {
+				timestamp = value
+			}
+		default:
+			return "", "", time.Time{}, errors.New("consistency: unsupported protobuf wire type")
+		}
+	}
+
+	return driver, revision, time.Unix(int64(timestamp), 0), nil
+}
+
+// appendVarintField appends a varint-typed field's tag and value.
+func appendVarintField(buf []byte, field int, v uint64) []byte {
+	buf = appendVarint(buf, uint64(field)<<3|wireVarint)
+	return appendVarint(buf, v)
+}
+
+// appendStringField appends a length-delimited field's tag, length, and bytes.
+func appendStringField(buf []byte, field int, s string) []byte {
+	buf = appendVarint(buf, uint64(field)<<3|wireBytes)
+	buf = appendVarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// appendVarint appends v as a base-128 varint.
+func appendVarint(buf []byte, v uint64) []byte {
+	for v >= 0x80 {
+		buf = append(buf, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(buf, byte(v))
+}
+
+// readVarint reads a base-128 varint off the front of buf, returning the
+// value and the remaining bytes.
+func readVarint(buf []byte) (uint64, []byte, error) {
+	var v uint64
+	var shift uint
+
+	for i, b := range buf {
+		v |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return v, buf[i+1:], nil
+		}
+		shift += 7
+	}
+
+	return 0, nil, errors.New("consistency: truncated varint")
+}
+
+// readString reads a length-delimited field's bytes off the front of buf.
+func readString(buf []byte) (string, []byte, error) {
+	length, buf, err := readVarint(buf)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if uint64(len(buf)) < length {
+		return "", nil, errors.New("consistency: truncated field")
+	}
+
+	return string(buf[:length]), buf[length:], nil
+}
@@ -0,0 +1,107 @@
+package schema
+
+// Kind distinguishes a Rewrite node from a Leaf node in an action's rewrite
+// tree.
+type Kind string
+
+// String -
+func (k Kind) String() string {
+	return string(k)
+}
+
+const (
+	RewriteKind Kind = "rewrite"
+	LeafKind    Kind = "leaf"
+)
+
+// RewriteType identifies how a Rewrite node's children combine.
+type RewriteType string
+
+// String -
+func (t RewriteType) String() string {
+	return string(t)
+}
+
+const (
+	Union        RewriteType = "union"
+	Intersection RewriteType = "intersection"
+	// Difference (the exclusion rewrite) subtracts every subsequent child
+	// from the first, e.g. "editors minus banned_users".
+	Difference RewriteType = "difference"
+)
+
+// LeafType identifies how a Leaf node resolves to tuples.
+type LeafType string
+
+// String -
+func (t LeafType) String() string {
+	return string(t)
+}
+
+const (
+	// TupleToUserSetType follows a tupleset relation to another object and
+	// resolves a (possibly different) relation on it, e.g. "parent.viewer".
+	TupleToUserSetType LeafType = "tuple_to_userset"
+	// ComputedUserSetType resolves another relation on the same object.
+	ComputedUserSetType LeafType = "computed_userset"
+)
+
+// Child is a node in an action's rewrite tree: either a Rewrite, combining
+// other children via a RewriteType, or a Leaf, resolving to tuples directly.
+type Child interface {
+	GetKind() string
+	GetType() string
+}
+
+// Rewrite combines Children using Type (union, intersection, or difference).
+type Rewrite struct {
+	Type     RewriteType
+	Children []Child
+}
+
+// GetKind -
+func (r Rewrite) GetKind() string {
+	return RewriteKind.String()
+}
+
+// GetType -
+func (r Rewrite) GetType() string {
+	return r.Type.String()
+}
+
+// Leaf resolves directly to the tuples stored on Value, either by following
+// a tuple-to-userset reference or a computed userset on the same object.
+type Leaf struct {
+	Type  LeafType
+	Value string
+}
+
+// GetKind -
+func (l Leaf) GetKind() string {
+	return LeafKind.String()
+}
+
+// GetType -
+func (l Leaf) GetType() string {
+	return l.Type.String()
+}
+
+// Action is a single named permission check, e.g. "view" or "edit", rooted
+// at Child.
+type Action struct {
+	Name  string
+	Child Child
+}
+
+// Entity is a namespace's schema definition: the actions it exposes and the
+// relations backing them.
+type Entity struct {
+	Name      string
+	Actions   []Action
+	Relations map[string]RelationDefinition
+}
+
+// Schema is a parsed DSL document, every namespace keyed by name.
+type Schema struct {
+	Entities map[string]Entity
+}
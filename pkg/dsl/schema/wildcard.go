@@ -0,0 +1,23 @@
+package schema
+
+import "errors"
+
+// ErrWildcardNotAllowed is returned when a wildcard subject is used against a
+// relation that was declared without wildcard support.
+var ErrWildcardNotAllowed = errors.New("wildcard subject not allowed on this relation")
+
+// RelationDefinition describes the subject types a relation accepts.
+type RelationDefinition struct {
+	Name          string
+	AllowWildcard bool
+}
+
+// ValidateWildcardSubject rejects a wildcard subject against a relation that
+// doesn't explicitly allow one.
+func ValidateWildcardSubject(def RelationDefinition, isWildcard bool) error {
+	if isWildcard && !def.AllowWildcard {
+		return ErrWildcardNotAllowed
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package tuple
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalidObject -
+var ErrInvalidObject = errors.New("invalid object")
+
+// WildcardRelation is the subject ID representing every principal in a
+// namespace, e.g. "user:*".
+const WildcardRelation = "*"
+
+// Object -
+type Object struct {
+	Namespace string
+	ID        string
+}
+
+// ConvertObject parses the "namespace:id" wire format into an Object.
+func ConvertObject(o string) (Object, error) {
+	parts := strings.SplitN(o, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return Object{}, ErrInvalidObject
+	}
+
+	return Object{Namespace: parts[0], ID: parts[1]}, nil
+}
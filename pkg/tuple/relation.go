@@ -0,0 +1,27 @@
+package tuple
+
+import "strings"
+
+// ELLIPSIS is the implicit relation used when a userset reference doesn't
+// name one explicitly, e.g. `parent#...`.
+const ELLIPSIS = "..."
+
+// Relation -
+type Relation string
+
+// String -
+func (r Relation) String() string {
+	return string(r)
+}
+
+// Split breaks a tuple-to-userset relation such as "parent.viewer" into the
+// tupleset relation ("parent") and the relation it forwards into on the
+// referenced object ("viewer"). A relation with no "." forwards into itself.
+func (r Relation) Split() []Relation {
+	parts := strings.SplitN(string(r), ".", 2)
+	if len(parts) == 1 {
+		return []Relation{r, r}
+	}
+
+	return []Relation{Relation(parts[0]), Relation(parts[1])}
+}
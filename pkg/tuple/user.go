@@ -0,0 +1,67 @@
+package tuple
+
+import "strings"
+
+// UserSet references a relation on another object, e.g. "group:eng#member".
+type UserSet struct {
+	Object   Object
+	Relation Relation
+}
+
+// User is either a concrete principal ("user:1"), an indirect userset
+// ("group:eng#member"), or a wildcard covering every subject in a namespace
+// ("user:*").
+type User struct {
+	ID        string
+	Namespace string
+	Wildcard  bool
+	UserSet   UserSet
+}
+
+// IsUser reports whether this is a direct principal (concrete or wildcard)
+// rather than an indirect userset.
+func (u User) IsUser() bool {
+	return u.UserSet.Object.ID == ""
+}
+
+// Equals reports whether u and other refer to the same subject. A wildcard
+// user matches any concrete subject sharing its namespace.
+func (u User) Equals(other User) bool {
+	if u.Wildcard {
+		return u.Namespace != "" && u.Namespace == other.Namespace
+	}
+
+	if !u.IsUser() || !other.IsUser() {
+		return u.UserSet == other.UserSet
+	}
+
+	return u.ID != "" && u.ID == other.ID
+}
+
+// ConvertUser parses the wire format for a subject: "namespace:id",
+// "namespace:id#relation", or the wildcard form "namespace:*".
+func ConvertUser(s string) User {
+	main, relation, hasRelation := strings.Cut(s, "#")
+
+	nsID := strings.SplitN(main, ":", 2)
+	if len(nsID) != 2 {
+		return User{ID: s, Namespace: "user"}
+	}
+
+	namespace, id := nsID[0], nsID[1]
+
+	if id == WildcardRelation {
+		return User{Wildcard: true, Namespace: namespace}
+	}
+
+	if hasRelation {
+		return User{
+			UserSet: UserSet{
+				Object:   Object{Namespace: namespace, ID: id},
+				Relation: Relation(relation),
+			},
+		}
+	}
+
+	return User{ID: id, Namespace: namespace}
+}